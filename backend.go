@@ -0,0 +1,47 @@
+// Copyright 2016 RapidLoop. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package skv
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrBackendNotFound is returned by a Backend's Get method when the key is
+// absent. KVStore translates it to the public ErrNotFound. Exported so that
+// Backend implementations outside this package can return it too.
+var ErrBackendNotFound = errors.New("skv: key not found in backend")
+
+// Backend is the storage engine underlying a KVStore. KVStore handles
+// encoding, tagging and locking; a Backend only needs to move bytes around.
+// Implementations must return ErrBackendNotFound from Get when the key does
+// not exist.
+type Backend interface {
+	// Get returns the raw value stored under key, or ErrBackendNotFound.
+	Get(key []byte) ([]byte, error)
+
+	// Put stores value under key, overwriting any existing value.
+	Put(key, value []byte) error
+
+	// PutWithTTL stores value under key, to be expired after ttl.
+	PutWithTTL(key, value []byte, ttl time.Duration) error
+
+	// Delete removes key. Implementations may choose to return nil even if
+	// key was not present, matching bitcask's behaviour.
+	Delete(key []byte) error
+
+	// Scan calls f with every key that has the given prefix. Scan stops and
+	// returns the error if f returns one.
+	Scan(prefix []byte, f func(key []byte) error) error
+
+	// Sift calls f with every key in the store. If f returns true, that key
+	// is deleted (this mirrors bitcask.Bitcask.Sift, which Sift is modeled
+	// on). Sift stops early only if f returns a non-nil error, which Sift
+	// then returns.
+	Sift(f func(key []byte) (bool, error)) error
+
+	// Close releases any resources held by the backend.
+	Close() error
+}
@@ -0,0 +1,238 @@
+package skv
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOpenMem(t *testing.T) {
+	db, err := Open[string]("mem://")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Put("key1", "value1"); err != nil {
+		t.Fatal(err)
+	}
+	val, err := db.Get("key1")
+	if err != nil {
+		t.Fatal(err)
+	} else if val != "value1" {
+		t.Fatalf("got \"%s\", expected \"value1\"", val)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMemTTLExpires(t *testing.T) {
+	db, err := Open[string]("mem://")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.PutWithTTL("key1", "value1", 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if _, err := db.Get("key1"); err != ErrNotFound {
+		t.Fatalf("got %v, expected ErrNotFound", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpenFS(t *testing.T) {
+	os.RemoveAll("skv-test-fs")
+	defer os.RemoveAll("skv-test-fs")
+
+	db, err := Open[string]("fs://skv-test-fs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Put("key1", "value1"); err != nil {
+		t.Fatal(err)
+	}
+	val, err := db.Get("key1")
+	if err != nil {
+		t.Fatal(err)
+	} else if val != "value1" {
+		t.Fatalf("got \"%s\", expected \"value1\"", val)
+	}
+	if err := db.Delete("key1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Get("key1"); err != ErrNotFound {
+		t.Fatalf("got %v, expected ErrNotFound", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFSTTLExpires(t *testing.T) {
+	os.RemoveAll("skv-test-fs")
+	defer os.RemoveAll("skv-test-fs")
+
+	db, err := Open[string]("fs://skv-test-fs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := db.PutWithTTL("key1", "value1", 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(30 * time.Millisecond)
+
+	if _, err := db.Get("key1"); err != ErrNotFound {
+		t.Fatalf("got %v, expected ErrNotFound", err)
+	}
+
+	keys, err := db.GetKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 0 {
+		t.Fatalf("got %v, expected GetKeys to omit the expired key", keys)
+	}
+}
+
+// externalBackend stands in for a Backend implemented outside this package:
+// it only ever returns the exported ErrBackendNotFound, never reaching into
+// package-internal state.
+type externalBackend struct {
+	mu     sync.Mutex
+	values map[string][]byte
+}
+
+func newExternalBackend() *externalBackend {
+	return &externalBackend{values: make(map[string][]byte)}
+}
+
+func (b *externalBackend) Get(key []byte) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	v, ok := b.values[string(key)]
+	if !ok {
+		return nil, ErrBackendNotFound
+	}
+	return v, nil
+}
+
+func (b *externalBackend) Put(key, value []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.values[string(key)] = append([]byte(nil), value...)
+	return nil
+}
+
+func (b *externalBackend) PutWithTTL(key, value []byte, ttl time.Duration) error {
+	return b.Put(key, value)
+}
+
+func (b *externalBackend) Delete(key []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.values, string(key))
+	return nil
+}
+
+func (b *externalBackend) Scan(prefix []byte, f func(key []byte) error) error {
+	b.mu.Lock()
+	keys := make([]string, 0, len(b.values))
+	for k := range b.values {
+		if strings.HasPrefix(k, string(prefix)) {
+			keys = append(keys, k)
+		}
+	}
+	b.mu.Unlock()
+	for _, k := range keys {
+		if err := f([]byte(k)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *externalBackend) Sift(f func(key []byte) (bool, error)) error {
+	b.mu.Lock()
+	keys := make([]string, 0, len(b.values))
+	for k := range b.values {
+		keys = append(keys, k)
+	}
+	b.mu.Unlock()
+	for _, k := range keys {
+		del, err := f([]byte(k))
+		if err != nil {
+			return err
+		}
+		if del {
+			b.Delete([]byte(k))
+		}
+	}
+	return nil
+}
+
+func (b *externalBackend) Close() error { return nil }
+
+// TestExternalBackendUsesExportedNotFoundSentinel confirms a Backend
+// implemented outside this package - which can only return the exported
+// ErrBackendNotFound, not the old unexported sentinel - works correctly
+// with every KVStore feature that distinguishes "not found" from a real
+// error: Get, tagging and the CAS version counter.
+func TestExternalBackendUsesExportedNotFoundSentinel(t *testing.T) {
+	db, err := OpenWithBackend[string](newExternalBackend())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.Get("missing"); err != ErrNotFound {
+		t.Fatalf("got %v, expected ErrNotFound", err)
+	}
+
+	if err := db.PutWithTags("key1", "value1", []string{"tagA"}); err != nil {
+		t.Fatal(err)
+	}
+	tagged, err := db.GetWithTag("tagA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tagged) != 1 || tagged[0] != "value1" {
+		t.Fatalf("got %v, expected [\"value1\"]", tagged)
+	}
+
+	v, err := db.BumpVersion("key1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != 1 {
+		t.Fatalf("got version %d, expected 1", v)
+	}
+	v, err = db.Version("key1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != 1 {
+		t.Fatalf("got version %d, expected 1", v)
+	}
+}
+
+func TestOpenWithBackend(t *testing.T) {
+	db, err := OpenWithBackend[string](newMemBackend())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Put("key1", "value1"); err != nil {
+		t.Fatal(err)
+	}
+	val, err := db.Get("key1")
+	if err != nil {
+		t.Fatal(err)
+	} else if val != "value1" {
+		t.Fatalf("got \"%s\", expected \"value1\"", val)
+	}
+}
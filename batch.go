@@ -0,0 +1,172 @@
+// Copyright 2016 RapidLoop. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package skv
+
+import (
+	"time"
+)
+
+// batchOp is one write queued in a Batch.
+type batchOp[T any] struct {
+	key   string
+	val   T      // zero value if del is true
+	value []byte // encoded val; unused if del is true
+	ttl   time.Duration
+	tags  []string
+	del   bool
+}
+
+// Batch accumulates a set of writes to apply to a KVStore as a single unit.
+// Create one with KVStore.Begin, queue writes with Put, PutWithTTL,
+// PutWithTags or Delete, then call Commit to apply them or Rollback to
+// discard them. A Batch is not safe for concurrent use.
+//
+// Committing a batch takes the store's write lock once and coalesces tag
+// index updates: if several queued writes share a tag, that tag's posting
+// list is read and rewritten once instead of once per write, which is the
+// main cost of populating a store through PutWithTags today.
+//
+// Commit is all-or-nothing: if any queued write fails, every key the batch
+// touched - primary keys and tag postings alike - is restored to its
+// pre-Commit state before the error is returned. The one gap is TTL: since
+// Backend has no way to read a key's remaining TTL, a key that's rolled
+// back comes back without the TTL it had before the batch, if any.
+type Batch[T any] struct {
+	kvs *KVStore[T]
+	ops []batchOp[T]
+}
+
+// Begin returns a new Batch for accumulating writes against kvs.
+func (kvs *KVStore[T]) Begin() *Batch[T] {
+	return &Batch[T]{kvs: kvs}
+}
+
+// Put queues a Put of value at key.
+func (b *Batch[T]) Put(key string, value T) error {
+	enc, err := b.kvs.codec.Encode(value)
+	if err != nil {
+		return err
+	}
+	b.ops = append(b.ops, batchOp[T]{key: key, val: value, value: enc})
+	return nil
+}
+
+// PutWithTTL queues a Put of value at key, to expire after ttl.
+func (b *Batch[T]) PutWithTTL(key string, value T, ttl time.Duration) error {
+	enc, err := b.kvs.codec.Encode(value)
+	if err != nil {
+		return err
+	}
+	b.ops = append(b.ops, batchOp[T]{key: key, val: value, value: enc, ttl: ttl})
+	return nil
+}
+
+// PutWithTags queues a Put of value at key, tagged with tags.
+func (b *Batch[T]) PutWithTags(key string, value T, tags []string) error {
+	enc, err := b.kvs.codec.Encode(value)
+	if err != nil {
+		return err
+	}
+	b.ops = append(b.ops, batchOp[T]{key: key, val: value, value: enc, tags: tags})
+	return nil
+}
+
+// Delete queues the removal of key.
+func (b *Batch[T]) Delete(key string) {
+	b.ops = append(b.ops, batchOp[T]{key: key, del: true})
+}
+
+// Rollback discards all queued writes. The Batch may be reused afterwards.
+func (b *Batch[T]) Rollback() {
+	b.ops = nil
+}
+
+// Commit applies every queued write: it takes the store's write lock once,
+// applies the primary key writes in order, then flushes one coalesced
+// update per tag touched by the batch. See the Batch doc comment for what
+// "all-or-nothing" means here.
+func (b *Batch[T]) Commit() error {
+	kvs := b.kvs
+	events, err := b.apply()
+	if err != nil {
+		return err
+	}
+	for _, ev := range events {
+		kvs.publish(ev)
+	}
+	b.ops = nil
+	return nil
+}
+
+// apply does the locked portion of Commit and returns the events to
+// publish once the lock is released, so a blocked or slow watcher can't
+// hold up other operations on the store.
+//
+// Every write goes through a txBackend wrapping the store's real Backend,
+// which remembers each key's state from before Commit started. If an op
+// fails partway through, tx.rollback restores every key the batch had
+// touched so far, so a failed Commit never leaves the store half-applied.
+func (b *Batch[T]) apply() ([]Event[T], error) {
+	kvs := b.kvs
+	kvs.mu.Lock()
+	defer kvs.mu.Unlock()
+
+	tx := newTxBackend(kvs.db)
+	events, err := b.applyOps(tx)
+	if err != nil {
+		tx.rollback()
+		return nil, err
+	}
+	return events, nil
+}
+
+// applyOps does the actual writing for apply, through db - a txBackend
+// during a real Commit, so every write it makes can be undone on failure.
+func (b *Batch[T]) applyOps(db Backend) ([]Event[T], error) {
+	kvs := b.kvs
+	tagAdds := make(map[string]map[string]struct{})
+	events := make([]Event[T], 0, len(b.ops))
+
+	for _, op := range b.ops {
+		if op.del {
+			if err := kvs.untagAllLocked(db, op.key); err != nil {
+				return nil, err
+			}
+			if err := db.Delete([]byte(op.key)); err != nil {
+				return nil, err
+			}
+			events = append(events, Event[T]{Key: op.key, Type: EventDelete})
+			continue
+		}
+
+		var err error
+		if op.ttl > 0 {
+			err = db.PutWithTTL([]byte(op.key), op.value, op.ttl)
+		} else {
+			err = db.Put([]byte(op.key), op.value)
+		}
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, Event[T]{Key: op.key, Value: op.val, Type: EventPut})
+
+		for _, tag := range op.tags {
+			keys := tagAdds[tag]
+			if keys == nil {
+				keys = make(map[string]struct{})
+				tagAdds[tag] = keys
+			}
+			keys[op.key] = struct{}{}
+		}
+	}
+
+	for tag, keys := range tagAdds {
+		if err := kvs.mergeTagLocked(db, tag, keys); err != nil {
+			return nil, err
+		}
+	}
+
+	return events, nil
+}
@@ -0,0 +1,148 @@
+package skv
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+// failAfterNPutsBackend wraps a Backend and fails the Nth Put/PutWithTTL
+// call it sees, to exercise Batch.Commit's rollback path.
+type failAfterNPutsBackend struct {
+	Backend
+	n int
+}
+
+var errInjectedFailure = errors.New("skv: injected test failure")
+
+func (b *failAfterNPutsBackend) Put(key, value []byte) error {
+	b.n--
+	if b.n == 0 {
+		return errInjectedFailure
+	}
+	return b.Backend.Put(key, value)
+}
+
+func (b *failAfterNPutsBackend) PutWithTTL(key, value []byte, ttl time.Duration) error {
+	b.n--
+	if b.n == 0 {
+		return errInjectedFailure
+	}
+	return b.Backend.PutWithTTL(key, value, ttl)
+}
+
+func TestBatchCommit(t *testing.T) {
+	os.RemoveAll("skv-test.db")
+	db, err := Open[string]("skv-test.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	b := db.Begin()
+	if err := b.Put("key1", "value1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.PutWithTags("key2", "value2", []string{"tagA"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.PutWithTags("key3", "value3", []string{"tagA"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	val, err := db.Get("key1")
+	if err != nil {
+		t.Fatal(err)
+	} else if val != "value1" {
+		t.Fatalf("got \"%s\", expected \"value1\"", val)
+	}
+
+	tagged, err := db.GetWithTag("tagA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tagged) != 2 {
+		t.Fatalf("got %d tagged values, expected 2", len(tagged))
+	}
+}
+
+func TestBatchRollback(t *testing.T) {
+	os.RemoveAll("skv-test.db")
+	db, err := Open[string]("skv-test.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	b := db.Begin()
+	if err := b.Put("key1", "value1"); err != nil {
+		t.Fatal(err)
+	}
+	b.Rollback()
+
+	if _, err := db.Get("key1"); err != ErrNotFound {
+		t.Fatalf("got %v, expected ErrNotFound", err)
+	}
+}
+
+func TestBatchCommitAtomicOnError(t *testing.T) {
+	backend := &failAfterNPutsBackend{Backend: newMemBackend(), n: 2}
+	db, err := OpenWithBackend[string](backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	b := db.Begin()
+	if err := b.Put("key1", "value1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.PutWithTags("key2", "value2", []string{"tagA"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Commit(); err != errInjectedFailure {
+		t.Fatalf("got %v, expected the injected failure", err)
+	}
+
+	if _, err := db.Get("key1"); err != ErrNotFound {
+		t.Fatalf("got %v, expected key1 to have been rolled back", err)
+	}
+	if _, err := db.Get("key2"); err != ErrNotFound {
+		t.Fatalf("got %v, expected key2 to have been rolled back", err)
+	}
+	tagged, err := db.GetWithTag("tagA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tagged) != 0 {
+		t.Fatalf("got %v, expected no keys under tagA after rollback", tagged)
+	}
+}
+
+func TestBatchDelete(t *testing.T) {
+	os.RemoveAll("skv-test.db")
+	db, err := Open[string]("skv-test.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := db.Put("key1", "value1"); err != nil {
+		t.Fatal(err)
+	}
+
+	b := db.Begin()
+	b.Delete("key1")
+	if err := b.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.Get("key1"); err != ErrNotFound {
+		t.Fatalf("got %v, expected ErrNotFound", err)
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2016 RapidLoop. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package skv
+
+import (
+	"time"
+
+	"git.mills.io/prologic/bitcask"
+)
+
+// bitcaskBackend adapts a *bitcask.Bitcask to the Backend interface. This is
+// the default, crash-safe, production backend.
+type bitcaskBackend struct {
+	db *bitcask.Bitcask
+}
+
+// newBitcaskBackend opens (creating if necessary) a bitcask database at
+// path.
+func newBitcaskBackend(path string) (*bitcaskBackend, error) {
+	db, err := bitcask.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &bitcaskBackend{db: db}, nil
+}
+
+func (b *bitcaskBackend) Get(key []byte) ([]byte, error) {
+	v, err := b.db.Get(key)
+	if err == bitcask.ErrKeyNotFound {
+		return nil, ErrBackendNotFound
+	}
+	return v, err
+}
+
+func (b *bitcaskBackend) Put(key, value []byte) error {
+	return b.db.Put(key, value)
+}
+
+func (b *bitcaskBackend) PutWithTTL(key, value []byte, ttl time.Duration) error {
+	return b.db.PutWithTTL(key, value, ttl)
+}
+
+func (b *bitcaskBackend) Delete(key []byte) error {
+	return b.db.Delete(key)
+}
+
+func (b *bitcaskBackend) Scan(prefix []byte, f func(key []byte) error) error {
+	return b.db.Scan(prefix, f)
+}
+
+func (b *bitcaskBackend) Sift(f func(key []byte) (bool, error)) error {
+	return b.db.Sift(f)
+}
+
+func (b *bitcaskBackend) Close() error {
+	return b.db.Close()
+}
@@ -0,0 +1,77 @@
+// Copyright 2016 RapidLoop. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package skv
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec encodes and decodes values of type T for storage. Use
+// OpenWithCodec to pick one other than the default, GobCodec.
+type Codec[T any] interface {
+	Encode(value T) ([]byte, error)
+	Decode(data []byte) (T, error)
+}
+
+// GobCodec is the default Codec, using encoding/gob - the same wire format
+// skv has always used. If T is an interface type, concrete types stored
+// through it must be registered with gob.Register first.
+type GobCodec[T any] struct{}
+
+func (GobCodec[T]) Encode(value T) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (GobCodec[T]) Decode(data []byte) (T, error) {
+	var value T
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&value)
+	return value, err
+}
+
+// JSONCodec encodes values as JSON. Unlike GobCodec it doesn't need
+// concrete types registered for an interface-typed T, and it's readable by
+// non-Go clients - e.g. a web dashboard, or the skv/httpd API, which is
+// JSON over the wire regardless of which Codec the underlying store uses.
+type JSONCodec[T any] struct{}
+
+func (JSONCodec[T]) Encode(value T) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+func (JSONCodec[T]) Decode(data []byte) (T, error) {
+	var value T
+	err := json.Unmarshal(data, &value)
+	return value, err
+}
+
+// ProtoCodec encodes values as protobuf messages, for cross-service use. A
+// generic function can't construct a new T for an arbitrary message type,
+// so New must return a fresh, empty T for Decode to unmarshal into.
+//
+//	codec := skv.ProtoCodec[*mypb.Record]{New: func() *mypb.Record { return new(mypb.Record) }}
+type ProtoCodec[T proto.Message] struct {
+	New func() T
+}
+
+func (c ProtoCodec[T]) Encode(value T) ([]byte, error) {
+	return proto.Marshal(value)
+}
+
+func (c ProtoCodec[T]) Decode(data []byte) (T, error) {
+	value := c.New()
+	if err := proto.Unmarshal(data, value); err != nil {
+		var zero T
+		return zero, err
+	}
+	return value, nil
+}
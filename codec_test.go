@@ -0,0 +1,98 @@
+package skv
+
+import (
+	"os"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestOpenWithCodecJSON(t *testing.T) {
+	os.RemoveAll("skv-test.db")
+	db, err := OpenWithCodec[string]("skv-test.db", JSONCodec[string]{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := db.Put("key1", "value1"); err != nil {
+		t.Fatal(err)
+	}
+	val, err := db.Get("key1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val != "value1" {
+		t.Fatalf("got %q, expected value1", val)
+	}
+}
+
+func TestOpenWithCodecGobIsDefault(t *testing.T) {
+	os.RemoveAll("skv-test.db")
+	db, err := Open[string]("skv-test.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, ok := db.codec.(GobCodec[string]); !ok {
+		t.Fatalf("got %T, expected GobCodec to be the default", db.codec)
+	}
+}
+
+func TestProtoCodecRoundTrip(t *testing.T) {
+	codec := ProtoCodec[*wrapperspb.StringValue]{
+		New: func() *wrapperspb.StringValue { return new(wrapperspb.StringValue) },
+	}
+
+	enc, err := codec.Encode(wrapperspb.String("value1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec, err := codec.Decode(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dec.GetValue() != "value1" {
+		t.Fatalf("got %q, expected \"value1\"", dec.GetValue())
+	}
+}
+
+func TestOpenWithCodecProto(t *testing.T) {
+	os.RemoveAll("skv-test.db")
+	codec := ProtoCodec[*wrapperspb.StringValue]{
+		New: func() *wrapperspb.StringValue { return new(wrapperspb.StringValue) },
+	}
+	db, err := OpenWithCodec[*wrapperspb.StringValue]("skv-test.db", codec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := db.Put("key1", wrapperspb.String("value1")); err != nil {
+		t.Fatal(err)
+	}
+	val, err := db.Get("key1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val.GetValue() != "value1" {
+		t.Fatalf("got %q, expected \"value1\"", val.GetValue())
+	}
+}
+
+func TestGetPropagatesDecodeError(t *testing.T) {
+	os.RemoveAll("skv-test.db")
+	db, err := OpenWithCodec[string]("skv-test.db", JSONCodec[string]{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := db.db.Put([]byte("key1"), []byte("not valid json")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Get("key1"); err == nil {
+		t.Fatal("expected an error decoding invalid JSON, got nil")
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright 2016 RapidLoop. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package skv
+
+import (
+	"encoding/base32"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// fsBackend is a crash-safe Backend that stores one file per key in a
+// directory, writing via a temp file + fsync + rename so a crash mid-write
+// never leaves a torn value behind. It has no in-process cache, so every
+// call touches disk; this trades throughput for simplicity and makes it a
+// reasonable choice for small, low-write deployments that want durability
+// without running bitcask.
+//
+// Each file holds an 8-byte big-endian unix-nano expiry (0 if the key has no
+// TTL) followed by the raw value bytes.
+type fsBackend struct {
+	dir string
+}
+
+// newFSBackend creates (if necessary) dir and returns a Backend that stores
+// one file per key under it.
+func newFSBackend(dir string) (*fsBackend, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, err
+	}
+	return &fsBackend{dir: dir}, nil
+}
+
+// fsEncodeKey maps a key to a filesystem-safe filename. Keys may contain
+// slashes, nulls and other characters that aren't valid in a path component,
+// so the key is base32-encoded rather than used directly.
+func fsEncodeKey(key []byte) string {
+	return base32.HexEncoding.WithPadding(base32.NoPadding).EncodeToString(key)
+}
+
+func fsDecodeKey(name string) ([]byte, error) {
+	return base32.HexEncoding.WithPadding(base32.NoPadding).DecodeString(name)
+}
+
+func (b *fsBackend) path(key []byte) string {
+	return filepath.Join(b.dir, fsEncodeKey(key))
+}
+
+func (b *fsBackend) Get(key []byte) ([]byte, error) {
+	data, err := os.ReadFile(b.path(key))
+	if os.IsNotExist(err) {
+		return nil, ErrBackendNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 8 {
+		return nil, ErrBackendNotFound
+	}
+	expiry := int64(binary.BigEndian.Uint64(data[:8]))
+	if expiry != 0 && time.Unix(0, expiry).Before(time.Now()) {
+		os.Remove(b.path(key))
+		return nil, ErrBackendNotFound
+	}
+	return data[8:], nil
+}
+
+func (b *fsBackend) put(key, value []byte, expiry time.Time) error {
+	var hdr [8]byte
+	if !expiry.IsZero() {
+		binary.BigEndian.PutUint64(hdr[:], uint64(expiry.UnixNano()))
+	}
+
+	tmp, err := os.CreateTemp(b.dir, "tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	if _, err := tmp.Write(hdr[:]); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if _, err := tmp.Write(value); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	return os.Rename(tmpName, b.path(key))
+}
+
+func (b *fsBackend) Put(key, value []byte) error {
+	return b.put(key, value, time.Time{})
+}
+
+func (b *fsBackend) PutWithTTL(key, value []byte, ttl time.Duration) error {
+	return b.put(key, value, time.Now().Add(ttl))
+}
+
+func (b *fsBackend) Delete(key []byte) error {
+	err := os.Remove(b.path(key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (b *fsBackend) Scan(prefix []byte, f func(key []byte) error) error {
+	return b.Sift(func(key []byte) (bool, error) {
+		if strings.HasPrefix(string(key), string(prefix)) {
+			if err := f(key); err != nil {
+				return false, err
+			}
+		}
+		return false, nil
+	})
+}
+
+// expired reports whether the file at key's path carries an expiry that has
+// already passed, reading only its 8-byte header rather than the full
+// value.
+func (b *fsBackend) expired(key []byte) (bool, error) {
+	f, err := os.Open(b.path(key))
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	var hdr [8]byte
+	if _, err := io.ReadFull(f, hdr[:]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return true, nil
+		}
+		return false, err
+	}
+	expiry := int64(binary.BigEndian.Uint64(hdr[:]))
+	return expiry != 0 && time.Unix(0, expiry).Before(time.Now()), nil
+}
+
+func (b *fsBackend) Sift(f func(key []byte) (bool, error)) error {
+	entries, err := os.ReadDir(b.dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), "tmp-") {
+			continue
+		}
+		key, err := fsDecodeKey(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		expired, err := b.expired(key)
+		if err != nil {
+			return err
+		}
+		if expired {
+			if err := b.Delete(key); err != nil {
+				return err
+			}
+			continue
+		}
+
+		shouldDelete, err := f(key)
+		if err != nil {
+			return err
+		}
+		if shouldDelete {
+			if err := b.Delete(key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (b *fsBackend) Close() error {
+	return nil
+}
@@ -0,0 +1,250 @@
+// Copyright 2016 RapidLoop. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package httpd exposes a skv.KVStore over a small HTTP/JSON API modeled on
+// Consul's /v1/kv/ endpoint. It's meant to let skv double as a tiny
+// config/service-discovery store that's reachable from non-Go processes.
+package httpd
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rapidloop/skv"
+)
+
+const (
+	keyPrefix = "/v1/kv/"
+	keysPath  = "/v1/keys"
+)
+
+// Handler serves a *skv.KVStore[T] over HTTP. Build one with NewHandler.
+//
+//	GET    /v1/kv/<key>                  - fetch the value stored at key
+//	PUT    /v1/kv/<key>?ttl=30s&tags=a,b - store a value, with an optional TTL and tags
+//	DELETE /v1/kv/<key>                  - remove key
+//	GET    /v1/kv/<prefix>?recurse=true  - fetch every key/value under prefix
+//	GET    /v1/kv/?tag=foo               - fetch every value tagged foo
+//	GET    /v1/keys                      - list every key
+//
+// Values are JSON-encoded on the wire, since gob is Go-only. PUT honours
+// compare-and-swap via an If-Match header or a ?cas=<version> query
+// parameter, checked against a version counter persisted in kv itself (see
+// skv.KVStore.CompareAndSwap) - so CAS holds up across a restart, and
+// across multiple Handlers serving the same KVStore, and two PUTs racing
+// the same compare-and-swap can't both succeed, since the check and the
+// write happen as one atomic step in the KVStore rather than three
+// separately-locked calls in the handler.
+type Handler[T any] struct {
+	kv *skv.KVStore[T]
+}
+
+// NewHandler returns an http.Handler exposing kv over the API documented on
+// Handler.
+func NewHandler[T any](kv *skv.KVStore[T]) http.Handler {
+	h := &Handler[T]{kv: kv}
+	mux := http.NewServeMux()
+	mux.HandleFunc(keysPath, h.handleKeys)
+	mux.HandleFunc(keyPrefix, h.handleKV)
+	return mux
+}
+
+// entry is the wire representation of one key/value pair.
+type entry[T any] struct {
+	Key   string `json:"key"`
+	Value T      `json:"value"`
+}
+
+func (h *Handler[T]) handleKeys(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httpError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	keys, err := h.kv.GetKeys()
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, keys)
+}
+
+func (h *Handler[T]) handleKV(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, keyPrefix)
+
+	if key == "" {
+		if tag := r.URL.Query().Get("tag"); tag != "" {
+			h.handleTag(w, r, tag)
+			return
+		}
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if r.URL.Query().Get("recurse") == "true" {
+			h.handlePrefix(w, r, key)
+			return
+		}
+		h.handleGet(w, r, key)
+	case http.MethodPut:
+		h.handlePut(w, r, key)
+	case http.MethodDelete:
+		h.handleDelete(w, r, key)
+	default:
+		httpError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	}
+}
+
+func (h *Handler[T]) handleGet(w http.ResponseWriter, r *http.Request, key string) {
+	val, err := h.kv.Get(key)
+	if err == skv.ErrNotFound {
+		httpError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		httpError(w, http.StatusInternalServerError, err)
+		return
+	}
+	version, err := h.kv.Version(key)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("ETag", strconv.FormatUint(version, 10))
+
+	// Content-type negotiation: []byte values can be served raw instead of
+	// as a base64 JSON string, for clients that asked for it.
+	if raw, ok := any(val).([]byte); ok && r.Header.Get("Accept") == "application/octet-stream" {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.WriteHeader(http.StatusOK)
+		w.Write(raw)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, val)
+}
+
+func (h *Handler[T]) handlePrefix(w http.ResponseWriter, r *http.Request, prefix string) {
+	keys, err := h.kv.GetKeys()
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, err)
+		return
+	}
+	entries := make([]entry[T], 0)
+	for _, key := range keys {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		val, err := h.kv.Get(key)
+		if err != nil {
+			continue // expired or deleted between GetKeys and Get
+		}
+		entries = append(entries, entry[T]{Key: key, Value: val})
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+func (h *Handler[T]) handleTag(w http.ResponseWriter, r *http.Request, tag string) {
+	vals, err := h.kv.GetWithTag(tag)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, vals)
+}
+
+func (h *Handler[T]) handlePut(w http.ResponseWriter, r *http.Request, key string) {
+	if key == "" {
+		httpError(w, http.StatusBadRequest, errors.New("key required"))
+		return
+	}
+
+	wantVersion, ok := parseCAS(r)
+	if !ok {
+		httpError(w, http.StatusPreconditionFailed, errors.New("cas mismatch"))
+		return
+	}
+
+	var val T
+	if err := json.NewDecoder(r.Body).Decode(&val); err != nil {
+		httpError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	q := r.URL.Query()
+	var tags []string
+	if t := q.Get("tags"); t != "" {
+		tags = strings.Split(t, ",")
+	}
+
+	var ttl time.Duration
+	if t := q.Get("ttl"); t != "" {
+		var err error
+		ttl, err = time.ParseDuration(t)
+		if err != nil {
+			httpError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	version, swapped, err := h.kv.CompareAndSwap(key, val, ttl, tags, wantVersion)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !swapped {
+		httpError(w, http.StatusPreconditionFailed, errors.New("cas mismatch"))
+		return
+	}
+	w.Header().Set("ETag", strconv.FormatUint(version, 10))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler[T]) handleDelete(w http.ResponseWriter, r *http.Request, key string) {
+	if key == "" {
+		httpError(w, http.StatusBadRequest, errors.New("key required"))
+		return
+	}
+	if err := h.kv.Delete(key); err != nil {
+		httpError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if err := h.kv.DeleteVersion(key); err != nil {
+		httpError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseCAS reads r's If-Match header or ?cas= query parameter (if either is
+// present) and returns the version PUT should require, or nil if neither
+// was given, meaning the PUT is unconditional. ok is false only when a cas
+// value was given but isn't a valid version, which the caller should treat
+// as a precondition failure exactly like a version mismatch.
+func parseCAS(r *http.Request) (want *uint64, ok bool) {
+	cas := r.Header.Get("If-Match")
+	if cas == "" {
+		cas = r.URL.Query().Get("cas")
+	}
+	if cas == "" {
+		return nil, true
+	}
+	v, err := strconv.ParseUint(cas, 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	return &v, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func httpError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,166 @@
+// Copyright 2016 RapidLoop. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package skv
+
+import (
+	"container/heap"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memBackend is a zero-dependency, in-memory Backend. It is meant for tests
+// and other short-lived uses - nothing is persisted, and everything is lost
+// on Close.
+type memBackend struct {
+	mu     sync.Mutex
+	data   map[string][]byte
+	expiry expiryHeap
+	index  map[string]*expiryItem
+}
+
+// expiryItem is one entry in the TTL heap.
+type expiryItem struct {
+	key   string
+	at    time.Time
+	index int
+}
+
+// expiryHeap is a container/heap of expiryItems ordered by expiry time, used
+// to find and evict expired keys without scanning the whole map.
+type expiryHeap []*expiryItem
+
+func (h expiryHeap) Len() int           { return len(h) }
+func (h expiryHeap) Less(i, j int) bool { return h[i].at.Before(h[j].at) }
+func (h expiryHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i]; h[i].index = i; h[j].index = j }
+func (h *expiryHeap) Push(x interface{}) {
+	item := x.(*expiryItem)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+func (h *expiryHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return item
+}
+
+// newMemBackend creates an empty in-memory backend.
+func newMemBackend() *memBackend {
+	return &memBackend{
+		data:  make(map[string][]byte),
+		index: make(map[string]*expiryItem),
+	}
+}
+
+// expireLocked drops any keys whose TTL has elapsed. Callers must hold mu.
+func (b *memBackend) expireLocked() {
+	now := time.Now()
+	for b.expiry.Len() > 0 && !b.expiry[0].at.After(now) {
+		item := heap.Pop(&b.expiry).(*expiryItem)
+		delete(b.data, item.key)
+		delete(b.index, item.key)
+	}
+}
+
+// clearTTLLocked removes any pending expiry for key. Callers must hold mu.
+func (b *memBackend) clearTTLLocked(key string) {
+	if item, ok := b.index[key]; ok {
+		heap.Remove(&b.expiry, item.index)
+		delete(b.index, key)
+	}
+}
+
+func (b *memBackend) Get(key []byte) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.expireLocked()
+	v, ok := b.data[string(key)]
+	if !ok {
+		return nil, ErrBackendNotFound
+	}
+	out := make([]byte, len(v))
+	copy(out, v)
+	return out, nil
+}
+
+func (b *memBackend) Put(key, value []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	k := string(key)
+	b.clearTTLLocked(k)
+	b.data[k] = append([]byte(nil), value...)
+	return nil
+}
+
+func (b *memBackend) PutWithTTL(key, value []byte, ttl time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	k := string(key)
+	b.clearTTLLocked(k)
+	b.data[k] = append([]byte(nil), value...)
+	item := &expiryItem{key: k, at: time.Now().Add(ttl)}
+	heap.Push(&b.expiry, item)
+	b.index[k] = item
+	return nil
+}
+
+func (b *memBackend) Delete(key []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	k := string(key)
+	b.clearTTLLocked(k)
+	delete(b.data, k)
+	return nil
+}
+
+func (b *memBackend) Scan(prefix []byte, f func(key []byte) error) error {
+	b.mu.Lock()
+	b.expireLocked()
+	keys := make([]string, 0, len(b.data))
+	for k := range b.data {
+		if strings.HasPrefix(k, string(prefix)) {
+			keys = append(keys, k)
+		}
+	}
+	b.mu.Unlock()
+
+	for _, k := range keys {
+		if err := f([]byte(k)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *memBackend) Sift(f func(key []byte) (bool, error)) error {
+	b.mu.Lock()
+	b.expireLocked()
+	keys := make([]string, 0, len(b.data))
+	for k := range b.data {
+		keys = append(keys, k)
+	}
+	b.mu.Unlock()
+
+	for _, k := range keys {
+		shouldDelete, err := f([]byte(k))
+		if err != nil {
+			return err
+		}
+		if shouldDelete {
+			b.mu.Lock()
+			b.clearTTLLocked(k)
+			delete(b.data, k)
+			b.mu.Unlock()
+		}
+	}
+	return nil
+}
+
+func (b *memBackend) Close() error {
+	return nil
+}
@@ -0,0 +1,26 @@
+// Copyright 2016 RapidLoop. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package skv
+
+import "strings"
+
+// openBackend resolves path to a Backend. A URL-style scheme picks the
+// engine: "mem://" for an in-memory store (the path component, if any, is
+// ignored), "fs://path" for one-file-per-key storage at path, and
+// "bitcask://path" for a bitcask store at path. A path with no recognized
+// scheme is opened as bitcask, for backward compatibility with pre-Backend
+// callers of Open.
+func openBackend(path string) (Backend, error) {
+	switch {
+	case path == "mem://" || strings.HasPrefix(path, "mem://"):
+		return newMemBackend(), nil
+	case strings.HasPrefix(path, "fs://"):
+		return newFSBackend(strings.TrimPrefix(path, "fs://"))
+	case strings.HasPrefix(path, "bitcask://"):
+		return newBitcaskBackend(strings.TrimPrefix(path, "bitcask://"))
+	default:
+		return newBitcaskBackend(path)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2016 RapidLoop. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package skv
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// errStopSift is an internal sentinel used to stop a Sift early without
+// asking it to delete the current key - Sift's bool return means "delete",
+// not "stop", so early termination has to go through its error path.
+var errStopSift = errors.New("skv: stop")
+
+// KeyValue pairs a key with its decoded value, for APIs that need to return
+// both - GetWithPrefix only returns values, which loses the key.
+type KeyValue[T any] struct {
+	Key   string
+	Value T
+}
+
+// SearchOptions bounds the results returned by Search.
+type SearchOptions struct {
+	// Limit caps the number of results returned. Zero means unlimited.
+	Limit int
+	// Offset skips this many matches before collecting results.
+	Offset int
+}
+
+// Search performs a case-insensitive substring search for query across
+// every value in the store, and returns the matching key/value pairs.
+//
+// For each stored value, Search first tries a fast path: a substring match
+// against the raw encoded bytes (as produced by the store's Codec). If that
+// misses - which happens for numeric or other values whose textual form
+// isn't a substring of their encoding - it falls back to matching against
+// the value's fmt.Sprintf("%v", ...) rendering.
+//
+// ctx is checked between keys, so a caller can bound or cancel a search
+// over a large store. opts may be nil for an unbounded search.
+func (kvs *KVStore[T]) Search(ctx context.Context, query string, opts *SearchOptions) ([]KeyValue[T], error) {
+	query = strings.ToLower(query)
+	output := make([]KeyValue[T], 0)
+	skipped := 0
+
+	err := kvs.db.Sift(func(key []byte) (bool, error) {
+		if isInternalKey(string(key)) {
+			return false, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+
+		raw, err := kvs.getRaw(string(key))
+		if err != nil {
+			return false, nil // expired or deleted since Sift started
+		}
+
+		val, err := kvs.codec.Decode(raw)
+		if err != nil {
+			return false, nil
+		}
+
+		matched := strings.Contains(strings.ToLower(string(raw)), query)
+		if !matched {
+			matched = strings.Contains(strings.ToLower(fmt.Sprintf("%v", val)), query)
+		}
+		if !matched {
+			return false, nil
+		}
+
+		if opts != nil && skipped < opts.Offset {
+			skipped++
+			return false, nil
+		}
+
+		output = append(output, KeyValue[T]{Key: string(key), Value: val})
+
+		if opts != nil && opts.Limit > 0 && len(output) >= opts.Limit {
+			return false, errStopSift
+		}
+		return false, nil
+	})
+	if err == errStopSift {
+		err = nil
+	}
+	return output, err
+}
+
+// ValueExists reports whether needle occurs as a substring of any value's
+// raw encoded bytes in the store, and if so, returns the first key it was
+// found under.
+func (kvs *KVStore[T]) ValueExists(needle []byte) (string, bool) {
+	var foundKey string
+	found := false
+
+	kvs.db.Sift(func(key []byte) (bool, error) {
+		if isInternalKey(string(key)) {
+			return false, nil
+		}
+		raw, err := kvs.getRaw(string(key))
+		if err != nil {
+			return false, nil
+		}
+		if bytes.Contains(raw, needle) {
+			foundKey = string(key)
+			found = true
+			return false, errStopSift
+		}
+		return false, nil
+	})
+
+	return foundKey, found
+}
+
+// PrefixScanKV is like GetWithPrefix, but returns the matching keys
+// alongside their values.
+func (kvs *KVStore[T]) PrefixScanKV(prefix string) ([]KeyValue[T], error) {
+	output := make([]KeyValue[T], 0)
+
+	err := kvs.db.Scan([]byte(prefix), func(key []byte) error {
+		val, err := kvs.Get(string(key))
+		if err != nil {
+			return err
+		}
+		output = append(output, KeyValue[T]{Key: string(key), Value: val})
+		return nil
+	})
+	return output, err
+}
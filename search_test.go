@@ -0,0 +1,98 @@
+package skv
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	os.RemoveAll("skv-test.db")
+	db, err := Open[string]("skv-test.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := db.Put("key1", "hello world"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Put("key2", "goodbye world"); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := db.Search(context.Background(), "HELLO", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Key != "key1" {
+		t.Fatalf("got %v, expected one match on key1", results)
+	}
+}
+
+func TestSearchOptions(t *testing.T) {
+	os.RemoveAll("skv-test.db")
+	db, err := Open[string]("skv-test.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for _, k := range []string{"a", "b", "c"} {
+		if err := db.Put(k, "match-me"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := db.Search(context.Background(), "match", &SearchOptions{Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d results, expected 2", len(results))
+	}
+}
+
+func TestValueExists(t *testing.T) {
+	os.RemoveAll("skv-test.db")
+	db, err := Open[string]("skv-test.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := db.Put("key1", "findable value"); err != nil {
+		t.Fatal(err)
+	}
+
+	if key, ok := db.ValueExists([]byte("findable")); !ok || key != "key1" {
+		t.Fatalf("got (%q, %v), expected (\"key1\", true)", key, ok)
+	}
+	if _, ok := db.ValueExists([]byte("nope")); ok {
+		t.Fatal("expected no match")
+	}
+}
+
+func TestPrefixScanKV(t *testing.T) {
+	os.RemoveAll("skv-test.db")
+	db, err := Open[string]("skv-test.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := db.Put("pfx-key1", "value1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Put("other-key", "value2"); err != nil {
+		t.Fatal(err)
+	}
+
+	kvs, err := db.PrefixScanKV("pfx-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kvs) != 1 || kvs[0].Key != "pfx-key1" || kvs[0].Value != "value1" {
+		t.Fatalf("got %v, expected one match on pfx-key1", kvs)
+	}
+}
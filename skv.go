@@ -10,30 +10,58 @@
 // The API is very simple - you can Put(), Get() or Delete() entries. These
 // methods are goroutine-safe.
 //
-// skv uses BoltDB for storage and the encoding/gob package for encoding and
-// decoding values. There are no other dependencies.
+// skv uses bitcask for storage by default and GobCodec for encoding and
+// decoding values. Storage is pluggable via the Backend interface - see
+// OpenWithBackend - and in-memory and filesystem backends are included for
+// tests and small deployments that don't want the bitcask dependency.
+// Encoding is pluggable via the Codec interface - see OpenWithCodec - for
+// callers that want JSON, protobuf, or some other format instead of gob.
 package skv
 
 import (
-	"bytes"
-	"encoding/gob"
 	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
-
-	"git.mills.io/prologic/bitcask"
 )
 
 const (
-	CacheTagPattern = "db_tag_%s"
+	// internalKeyPrefix marks keys that hold skv's own bookkeeping (tag
+	// postings, reverse tag entries) rather than a user Put. The leading
+	// NUL makes an accidental collision with a real Put key extremely
+	// unlikely. GetKeys, GetAll and Search all skip keys with this prefix
+	// so bookkeeping never surfaces as user data.
+	internalKeyPrefix = "\x00skv\x00"
+
+	CacheTagPattern = internalKeyPrefix + "tag_%s"
+
+	// cacheKeyTagsPattern is the reverse index: it holds the set of tags a
+	// key is a member of, so Delete, DeleteTag and RetagKey can update a
+	// key's tags without scanning every tag's posting list.
+	cacheKeyTagsPattern = internalKeyPrefix + "keytags_%s"
 )
 
+// isInternalKey reports whether key holds skv bookkeeping rather than a
+// value a caller Put, so iteration over the backend's keyspace can skip it.
+func isInternalKey(key string) bool {
+	return strings.HasPrefix(key, internalKeyPrefix)
+}
+
 // KVStore represents the key value store. Use the Open() method to create
 // one, and Close() it when done.
 type KVStore[T any] struct {
-	db *bitcask.Bitcask
-	mu sync.RWMutex
+	db    Backend
+	codec Codec[T]
+	mu    sync.RWMutex
+
+	closed    chan struct{}
+	closeOnce sync.Once
+
+	watchMu              sync.Mutex
+	watchers             []*watcher[T]
+	watchWG              sync.WaitGroup
+	expiryScannerStarted bool
 }
 
 var (
@@ -50,24 +78,51 @@ var (
 // leading directories must have been created already. File is created with
 // mode 0640 if needed.
 //
-// Because of BoltDB restrictions, only one process may open the file at a
-// time. Attempts to open the file from another process will fail with a
-// timeout error.
+// path may also be a URL-style string selecting a different backend, e.g.
+// "mem://" for an in-memory store or "fs://some/dir" for one file per key.
+// See OpenWithBackend to pass a custom Backend directly.
+//
+// Because of BoltDB restrictions, only one process may open a bitcask file
+// at a time. Attempts to open the file from another process will fail with
+// a timeout error.
 func Open[T any](path string) (*KVStore[T], error) {
-	db, err := bitcask.Open(path)
+	db, err := openBackend(path)
+	if err != nil {
+		return nil, err
+	}
+	return newKVStore[T](db, GobCodec[T]{}), nil
+}
+
+// OpenWithBackend creates a KVStore backed by an already-constructed
+// Backend, using the default GobCodec. Use this to plug in a custom storage
+// engine, or to share one Backend's lifecycle management outside of skv.
+func OpenWithBackend[T any](b Backend) (*KVStore[T], error) {
+	return newKVStore[T](b, GobCodec[T]{}), nil
+}
+
+// OpenWithCodec is like Open, but encodes and decodes values with c instead
+// of the default GobCodec - e.g. JSONCodec for language interop, or
+// ProtoCodec for cross-service use.
+func OpenWithCodec[T any](path string, c Codec[T]) (*KVStore[T], error) {
+	db, err := openBackend(path)
 	if err != nil {
 		return nil, err
 	}
-	kv := KVStore[T]{
-		db: db,
-		mu: sync.RWMutex{},
+	return newKVStore[T](db, c), nil
+}
+
+func newKVStore[T any](b Backend, c Codec[T]) *KVStore[T] {
+	return &KVStore[T]{
+		db:     b,
+		codec:  c,
+		closed: make(chan struct{}),
 	}
-	return &kv, nil
 }
 
-// Put an entry into the store. The passed value is gob-encoded and stored.
-// The key can be an empty string, but the value cannot be nil - if it is,
-// Put() returns ErrBadValue.
+// Put an entry into the store. The passed value is encoded with the
+// store's Codec (GobCodec by default) and stored. The key can be an empty
+// string, but the value cannot be nil - if it is, Put() returns
+// ErrBadValue.
 //
 //	err := store.Put("key42", 156)
 //	err := store.Put("key42", "this is a string")
@@ -77,83 +132,59 @@ func Open[T any](path string) (*KVStore[T], error) {
 //	}
 //	err := store.Put("key43", m)
 func (kvs *KVStore[T]) Put(key string, value T) error {
-	var buf bytes.Buffer
-	if err := gob.NewEncoder(&buf).Encode(value); err != nil {
+	enc, err := kvs.codec.Encode(value)
+	if err != nil {
+		return err
+	}
+	if err := kvs.db.Put([]byte(key), enc); err != nil {
 		return err
 	}
-	return kvs.db.Put([]byte(key), buf.Bytes())
+	kvs.publish(Event[T]{Key: key, Value: value, Type: EventPut})
+	return nil
 }
 
 // Put an entry into the store with a TTL to expire the entry
 func (kvs *KVStore[T]) PutWithTTL(key string, value T, ttl time.Duration) error {
-	var buf bytes.Buffer
-	if err := gob.NewEncoder(&buf).Encode(value); err != nil {
+	enc, err := kvs.codec.Encode(value)
+	if err != nil {
 		return err
 	}
-	return kvs.db.PutWithTTL([]byte(key), buf.Bytes(), ttl)
+	if err := kvs.db.PutWithTTL([]byte(key), enc, ttl); err != nil {
+		return err
+	}
+	kvs.publish(Event[T]{Key: key, Value: value, Type: EventPut})
+	return nil
 }
 
 func (kvs *KVStore[T]) PutWithTags(key string, value T, tags []string) error {
-	var buf bytes.Buffer
-	if err := gob.NewEncoder(&buf).Encode(value); err != nil {
+	enc, err := kvs.codec.Encode(value)
+	if err != nil {
 		return err
 	}
-	err := kvs.db.Put([]byte(key), buf.Bytes())
-	if err != nil {
+	if err := kvs.db.Put([]byte(key), enc); err != nil {
 		return err
 	}
-	return kvs.saveTags(key, tags)
+	if err := kvs.saveTags(key, tags); err != nil {
+		return err
+	}
+	kvs.publish(Event[T]{Key: key, Value: value, Type: EventPut})
+	return nil
 }
 
 // Put an entry into the store with a TTL to expire the entry
 func (kvs *KVStore[T]) PutWithTagsAndTTL(key string, value T, ttl time.Duration, tags []string) error {
-	var buf bytes.Buffer
-	if err := gob.NewEncoder(&buf).Encode(value); err != nil {
+	enc, err := kvs.codec.Encode(value)
+	if err != nil {
 		return err
 	}
-	err := kvs.db.PutWithTTL([]byte(key), buf.Bytes(), ttl)
-	if err != nil {
+	if err := kvs.db.PutWithTTL([]byte(key), enc, ttl); err != nil {
 		return err
 	}
 
-	return kvs.saveTags(key, tags)
-}
-
-func (kvs *KVStore[T]) saveTags(key string, tags []string) error {
-	// get the tags
-	kvs.mu.Lock()
-	defer kvs.mu.Unlock()
-
-	for _, tag := range tags {
-
-		var cacheKeys map[string]struct{}
-
-		tagKey := []byte(fmt.Sprintf(CacheTagPattern, tag))
-		if result, err := kvs.db.Get(tagKey); err == nil {
-			e := new(map[string]struct{})
-			d := gob.NewDecoder(bytes.NewReader(result))
-			d.Decode(e)
-			cacheKeys = *e
-		}
-
-		if cacheKeys == nil {
-			cacheKeys = make(map[string]struct{})
-		}
-
-		if _, exists := cacheKeys[key]; exists {
-			continue
-		}
-
-		cacheKeys[key] = struct{}{}
-
-		var buf bytes.Buffer
-		if err := gob.NewEncoder(&buf).Encode(cacheKeys); err != nil {
-			return err
-		}
-
-		kvs.db.Put(tagKey, buf.Bytes())
-
+	if err := kvs.saveTags(key, tags); err != nil {
+		return err
 	}
+	kvs.publish(Event[T]{Key: key, Value: value, Type: EventPut})
 	return nil
 }
 
@@ -179,21 +210,23 @@ func (kvs *KVStore[T]) saveTags(key string, tags []string) error {
 //	    fmt.Println("entry is present")
 //	}
 func (kvs *KVStore[T]) Get(key string) (T, error) {
-	output := new(T)
-
-	v, e := kvs.db.Get([]byte(key))
-
-	if e == bitcask.ErrKeyNotFound {
-		return *output, ErrNotFound
-	}
-
+	v, e := kvs.getRaw(key)
 	if e != nil {
-		return *output, e
+		var zero T
+		return zero, e
 	}
 
-	d := gob.NewDecoder(bytes.NewReader(v))
-	d.Decode(output)
-	return *output, e
+	return kvs.codec.Decode(v)
+}
+
+// getRaw fetches the encoded bytes stored at key, translating the
+// backend's not-found error to the public ErrNotFound.
+func (kvs *KVStore[T]) getRaw(key string) ([]byte, error) {
+	v, e := kvs.db.Get([]byte(key))
+	if e == ErrBackendNotFound {
+		return nil, ErrNotFound
+	}
+	return v, e
 }
 
 func (kvs *KVStore[T]) GetWithPrefix(p string) ([]T, error) {
@@ -213,32 +246,25 @@ func (kvs *KVStore[T]) GetWithPrefix(p string) ([]T, error) {
 
 func (kvs *KVStore[T]) GetWithTag(tag string) ([]T, error) {
 	output := make([]T, 0)
-	tagKey := []byte(fmt.Sprintf(CacheTagPattern, tag))
-	updateTags := false
 
 	// lock the tags mutex
 	kvs.mu.Lock()
 	defer kvs.mu.Unlock()
 
-	var cacheKeys map[string]struct{}
-	if result, err := kvs.db.Get(tagKey); err == nil {
-		e := new(map[string]struct{})
-		d := gob.NewDecoder(bytes.NewReader(result))
-		d.Decode(e)
-		cacheKeys = *e
-	}
-
-	if cacheKeys == nil {
-		return output, nil
+	postings, err := kvs.loadPostings(kvs.db, fmt.Sprintf(CacheTagPattern, tag))
+	if err != nil {
+		return output, err
 	}
 
-	for key := range cacheKeys {
+	updateTags := false
+	for key := range postings {
 		item, err := kvs.Get(key)
 
 		// key might have expired or deleted
 		if err == ErrNotFound {
-			delete(cacheKeys, key)
+			delete(postings, key)
 			updateTags = true
+			kvs.untagKeyLocked(key, tag)
 			continue
 		}
 
@@ -249,22 +275,38 @@ func (kvs *KVStore[T]) GetWithTag(tag string) ([]T, error) {
 	}
 
 	if updateTags {
-		var buf bytes.Buffer
-		if err := gob.NewEncoder(&buf).Encode(cacheKeys); err != nil {
+		if err := kvs.savePostings(kvs.db, fmt.Sprintf(CacheTagPattern, tag), postings); err != nil {
 			return make([]T, 0), err
 		}
-
-		kvs.db.Put(tagKey, buf.Bytes())
 	}
 
 	return output, nil
 }
 
 // Delete the entry with the given key. If no such key is present in the store,
-// it returns ErrNotFound.
+// it returns ErrNotFound. Delete also removes key from every tag's posting
+// list it is a member of, so tags don't accumulate dead keys.
 //
 //	store.Delete("key42")
 func (kvs *KVStore[T]) Delete(key string) error {
+	if err := kvs.deleteLocked(key); err != nil {
+		return err
+	}
+	kvs.publish(Event[T]{Key: key, Type: EventDelete})
+	return nil
+}
+
+// deleteLocked does the locked portion of Delete. Splitting it out lets
+// Delete publish after releasing kvs.mu, so a blocked or slow watcher can't
+// hold up other operations on the store - the same reason Batch.apply is
+// split from Batch.Commit.
+func (kvs *KVStore[T]) deleteLocked(key string) error {
+	kvs.mu.Lock()
+	defer kvs.mu.Unlock()
+
+	if err := kvs.untagAllLocked(kvs.db, key); err != nil {
+		return err
+	}
 	return kvs.db.Delete([]byte(key))
 }
 
@@ -276,6 +318,9 @@ func (kvs *KVStore[T]) GetKeys() ([]string, error) {
 	var kl []string
 
 	err := kvs.db.Sift(func(key []byte) (bool, error) {
+		if isInternalKey(string(key)) {
+			return false, nil
+		}
 		kl = append(kl, string(key))
 		return false, nil
 	})
@@ -291,6 +336,9 @@ func (kvs *KVStore[T]) GetAll() ([]T, error) {
 	var kl []T
 
 	err := kvs.db.Sift(func(key []byte) (bool, error) {
+		if isInternalKey(string(key)) {
+			return false, nil
+		}
 		entry, err := kvs.Get(string(key))
 		if err != nil {
 			return false, err
@@ -302,7 +350,11 @@ func (kvs *KVStore[T]) GetAll() ([]T, error) {
 	return kl, err
 }
 
-// Close closes the key-value store file.
+// Close closes the key-value store file. It also terminates any active
+// Watch subscriptions and their background expiry scanner, closing each
+// subscription's channel.
 func (kvs *KVStore[T]) Close() error {
+	kvs.closeOnce.Do(func() { close(kvs.closed) })
+	kvs.watchWG.Wait()
 	return kvs.db.Close()
 }
@@ -0,0 +1,255 @@
+// Copyright 2016 RapidLoop. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package skv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Tags are indexed both ways: CacheTagPattern holds the posting list (the
+// set of keys) for a tag, and cacheKeyTagsPattern holds the reverse set (the
+// tags a key belongs to). The reverse index is what lets Delete, DeleteTag
+// and RetagKey update a key's tags without having to scan every tag in the
+// store.
+//
+// Posting lists are stored as a flat sequence of length-prefixed keys
+// rather than a gob-encoded map, since a tag can have thousands of members
+// and gob's map encoding carries a lot of overhead per entry.
+
+// encodePostings serializes a set of keys as [uvarint length][bytes]...
+func encodePostings(keys map[string]struct{}) []byte {
+	var buf bytes.Buffer
+	var lenBuf [binary.MaxVarintLen64]byte
+	for key := range keys {
+		n := binary.PutUvarint(lenBuf[:], uint64(len(key)))
+		buf.Write(lenBuf[:n])
+		buf.WriteString(key)
+	}
+	return buf.Bytes()
+}
+
+// decodePostings parses the format written by encodePostings.
+func decodePostings(data []byte) (map[string]struct{}, error) {
+	keys := make(map[string]struct{})
+	r := bytes.NewReader(data)
+	for r.Len() > 0 {
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		keys[string(buf)] = struct{}{}
+	}
+	return keys, nil
+}
+
+// loadPostings reads and decodes the posting list stored at storageKey. A
+// missing key is not an error - it just means an empty set.
+func (kvs *KVStore[T]) loadPostings(db Backend, storageKey string) (map[string]struct{}, error) {
+	raw, err := db.Get([]byte(storageKey))
+	if err == ErrBackendNotFound {
+		return make(map[string]struct{}), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodePostings(raw)
+}
+
+// savePostings writes keys to storageKey, or deletes storageKey entirely if
+// keys is empty, so an emptied-out tag or reverse entry doesn't linger.
+func (kvs *KVStore[T]) savePostings(db Backend, storageKey string, keys map[string]struct{}) error {
+	if len(keys) == 0 {
+		return db.Delete([]byte(storageKey))
+	}
+	return db.Put([]byte(storageKey), encodePostings(keys))
+}
+
+// saveTags adds key to each of tags' posting lists, and records tags in
+// key's reverse entry. Callers must not hold kvs.mu.
+func (kvs *KVStore[T]) saveTags(key string, tags []string) error {
+	kvs.mu.Lock()
+	defer kvs.mu.Unlock()
+
+	for _, tag := range tags {
+		if err := kvs.mergeTagLocked(kvs.db, tag, map[string]struct{}{key: {}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeTagLocked adds keys to tag's posting list with a single
+// read-modify-write, instead of one per key, and updates each key's reverse
+// entry to include tag. Callers must hold kvs.mu. db is a parameter, rather
+// than always kvs.db, so Batch.apply can route the same logic through a
+// txBackend for rollback.
+func (kvs *KVStore[T]) mergeTagLocked(db Backend, tag string, keys map[string]struct{}) error {
+	postings, err := kvs.loadPostings(db, fmt.Sprintf(CacheTagPattern, tag))
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	for key := range keys {
+		if _, exists := postings[key]; exists {
+			continue
+		}
+		postings[key] = struct{}{}
+		changed = true
+
+		keyTags, err := kvs.loadPostings(db, fmt.Sprintf(cacheKeyTagsPattern, key))
+		if err != nil {
+			return err
+		}
+		keyTags[tag] = struct{}{}
+		if err := kvs.savePostings(db, fmt.Sprintf(cacheKeyTagsPattern, key), keyTags); err != nil {
+			return err
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	return kvs.savePostings(db, fmt.Sprintf(CacheTagPattern, tag), postings)
+}
+
+// untagKeyLocked removes key from tag's posting list and from key's reverse
+// entry. Callers must hold kvs.mu.
+func (kvs *KVStore[T]) untagKeyLocked(key, tag string) error {
+	postings, err := kvs.loadPostings(kvs.db, fmt.Sprintf(CacheTagPattern, tag))
+	if err != nil {
+		return err
+	}
+	delete(postings, key)
+	if err := kvs.savePostings(kvs.db, fmt.Sprintf(CacheTagPattern, tag), postings); err != nil {
+		return err
+	}
+
+	keyTags, err := kvs.loadPostings(kvs.db, fmt.Sprintf(cacheKeyTagsPattern, key))
+	if err != nil {
+		return err
+	}
+	delete(keyTags, tag)
+	return kvs.savePostings(kvs.db, fmt.Sprintf(cacheKeyTagsPattern, key), keyTags)
+}
+
+// untagAllLocked removes key from every tag it belongs to, and drops its
+// reverse entry. Callers must hold kvs.mu. db is a parameter, rather than
+// always kvs.db, so Batch.apply can route the same logic through a
+// txBackend for rollback.
+func (kvs *KVStore[T]) untagAllLocked(db Backend, key string) error {
+	keyTags, err := kvs.loadPostings(db, fmt.Sprintf(cacheKeyTagsPattern, key))
+	if err != nil {
+		return err
+	}
+	for tag := range keyTags {
+		postings, err := kvs.loadPostings(db, fmt.Sprintf(CacheTagPattern, tag))
+		if err != nil {
+			return err
+		}
+		delete(postings, key)
+		if err := kvs.savePostings(db, fmt.Sprintf(CacheTagPattern, tag), postings); err != nil {
+			return err
+		}
+	}
+	return db.Delete([]byte(fmt.Sprintf(cacheKeyTagsPattern, key)))
+}
+
+// DeleteTag drops tag entirely: its posting list, and its entry in every
+// member key's reverse tag set. It does not delete the keys themselves.
+func (kvs *KVStore[T]) DeleteTag(tag string) error {
+	kvs.mu.Lock()
+	defer kvs.mu.Unlock()
+
+	postings, err := kvs.loadPostings(kvs.db, fmt.Sprintf(CacheTagPattern, tag))
+	if err != nil {
+		return err
+	}
+
+	for key := range postings {
+		keyTags, err := kvs.loadPostings(kvs.db, fmt.Sprintf(cacheKeyTagsPattern, key))
+		if err != nil {
+			return err
+		}
+		delete(keyTags, tag)
+		if err := kvs.savePostings(kvs.db, fmt.Sprintf(cacheKeyTagsPattern, key), keyTags); err != nil {
+			return err
+		}
+	}
+
+	return kvs.db.Delete([]byte(fmt.Sprintf(CacheTagPattern, tag)))
+}
+
+// RetagKey replaces the set of tags key belongs to with newTags, updating
+// both the posting list of every tag added or removed and key's own reverse
+// entry.
+func (kvs *KVStore[T]) RetagKey(key string, newTags []string) error {
+	kvs.mu.Lock()
+	defer kvs.mu.Unlock()
+
+	oldTags, err := kvs.loadPostings(kvs.db, fmt.Sprintf(cacheKeyTagsPattern, key))
+	if err != nil {
+		return err
+	}
+
+	newSet := make(map[string]struct{}, len(newTags))
+	for _, tag := range newTags {
+		newSet[tag] = struct{}{}
+	}
+
+	for tag := range oldTags {
+		if _, keep := newSet[tag]; keep {
+			continue
+		}
+		postings, err := kvs.loadPostings(kvs.db, fmt.Sprintf(CacheTagPattern, tag))
+		if err != nil {
+			return err
+		}
+		delete(postings, key)
+		if err := kvs.savePostings(kvs.db, fmt.Sprintf(CacheTagPattern, tag), postings); err != nil {
+			return err
+		}
+	}
+
+	for tag := range newSet {
+		if _, had := oldTags[tag]; had {
+			continue
+		}
+		postings, err := kvs.loadPostings(kvs.db, fmt.Sprintf(CacheTagPattern, tag))
+		if err != nil {
+			return err
+		}
+		postings[key] = struct{}{}
+		if err := kvs.savePostings(kvs.db, fmt.Sprintf(CacheTagPattern, tag), postings); err != nil {
+			return err
+		}
+	}
+
+	return kvs.savePostings(kvs.db, fmt.Sprintf(cacheKeyTagsPattern, key), newSet)
+}
+
+// GetKeysByTag returns every key currently carrying tag.
+func (kvs *KVStore[T]) GetKeysByTag(tag string) ([]string, error) {
+	kvs.mu.RLock()
+	defer kvs.mu.RUnlock()
+
+	postings, err := kvs.loadPostings(kvs.db, fmt.Sprintf(CacheTagPattern, tag))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(postings))
+	for key := range postings {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
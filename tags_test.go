@@ -0,0 +1,150 @@
+package skv
+
+import (
+	"os"
+	"testing"
+)
+
+func TestDeleteRemovesFromTagIndex(t *testing.T) {
+	os.RemoveAll("skv-test.db")
+	db, err := Open[string]("skv-test.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := db.PutWithTags("key1", "value1", []string{"tagA"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Delete("key1"); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := db.GetKeysByTag("tagA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 0 {
+		t.Fatalf("got %v, expected no keys left under tagA", keys)
+	}
+}
+
+func TestGetAllSkipsTagBookkeeping(t *testing.T) {
+	os.RemoveAll("skv-test.db")
+	db, err := Open[string]("skv-test.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := db.PutWithTags("key1", "hello world", []string{"greeting"}); err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := db.GetAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 1 || all[0] != "hello world" {
+		t.Fatalf("got %v, expected [hello world]", all)
+	}
+
+	keys, err := db.GetKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0] != "key1" {
+		t.Fatalf("got %v, expected [key1]", keys)
+	}
+}
+
+func TestDeleteTag(t *testing.T) {
+	os.RemoveAll("skv-test.db")
+	db, err := Open[string]("skv-test.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := db.PutWithTags("key1", "value1", []string{"tagA", "tagB"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DeleteTag("tagA"); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := db.GetKeysByTag("tagA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 0 {
+		t.Fatalf("got %v, expected tagA to be gone", keys)
+	}
+
+	// key1 should still carry tagB
+	keys, err = db.GetKeysByTag("tagB")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0] != "key1" {
+		t.Fatalf("got %v, expected [key1] under tagB", keys)
+	}
+
+	// and key1's value should be untouched
+	val, err := db.Get("key1")
+	if err != nil {
+		t.Fatal(err)
+	} else if val != "value1" {
+		t.Fatalf("got %q, expected value1", val)
+	}
+}
+
+func TestRetagKey(t *testing.T) {
+	os.RemoveAll("skv-test.db")
+	db, err := Open[string]("skv-test.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := db.PutWithTags("key1", "value1", []string{"tagA", "tagB"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.RetagKey("key1", []string{"tagB", "tagC"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if keys, err := db.GetKeysByTag("tagA"); err != nil || len(keys) != 0 {
+		t.Fatalf("got %v, %v, expected no keys under tagA", keys, err)
+	}
+	if keys, err := db.GetKeysByTag("tagB"); err != nil || len(keys) != 1 || keys[0] != "key1" {
+		t.Fatalf("got %v, %v, expected [key1] under tagB", keys, err)
+	}
+	if keys, err := db.GetKeysByTag("tagC"); err != nil || len(keys) != 1 || keys[0] != "key1" {
+		t.Fatalf("got %v, %v, expected [key1] under tagC", keys, err)
+	}
+}
+
+func TestGetKeysByTag(t *testing.T) {
+	os.RemoveAll("skv-test.db")
+	db, err := Open[string]("skv-test.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := db.PutWithTags("key1", "value1", []string{"tagA"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.PutWithTags("key2", "value2", []string{"tagA"}); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := db.GetKeysByTag("tagA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("got %v, expected 2 keys under tagA", keys)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2016 RapidLoop. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package skv
+
+import "time"
+
+// txBackend wraps a Backend, remembering each key's value (or absence) from
+// before its first Put, PutWithTTL or Delete through the wrapper, so a
+// failed Batch.Commit can restore every key the batch touched back to
+// where it started. Later writes to an already-seen key don't re-snapshot
+// it - rollback only ever needs the value as of the start of the batch.
+//
+// txBackend doesn't buffer writes; it applies them to the underlying
+// Backend immediately and relies on rollback to undo them. That's enough
+// for Batch, whose writes are already serialized under kvs.mu, but makes
+// txBackend unsuitable for anything that needs isolation from concurrent
+// readers mid-transaction.
+type txBackend struct {
+	Backend
+	seen map[string]bool
+	undo []txUndo
+}
+
+// txUndo restores one key to its pre-transaction state.
+type txUndo struct {
+	key      []byte
+	hadValue bool
+	value    []byte
+}
+
+func newTxBackend(b Backend) *txBackend {
+	return &txBackend{Backend: b, seen: make(map[string]bool)}
+}
+
+// snapshot records key's current value, or its absence, the first time
+// txBackend is asked to write it.
+func (tx *txBackend) snapshot(key []byte) {
+	k := string(key)
+	if tx.seen[k] {
+		return
+	}
+	tx.seen[k] = true
+
+	if prev, err := tx.Backend.Get(key); err == nil {
+		tx.undo = append(tx.undo, txUndo{key: key, hadValue: true, value: prev})
+	} else {
+		tx.undo = append(tx.undo, txUndo{key: key})
+	}
+}
+
+func (tx *txBackend) Put(key, value []byte) error {
+	tx.snapshot(key)
+	return tx.Backend.Put(key, value)
+}
+
+func (tx *txBackend) PutWithTTL(key, value []byte, ttl time.Duration) error {
+	tx.snapshot(key)
+	return tx.Backend.PutWithTTL(key, value, ttl)
+}
+
+func (tx *txBackend) Delete(key []byte) error {
+	tx.snapshot(key)
+	return tx.Backend.Delete(key)
+}
+
+// rollback restores every key tx has seen to its pre-transaction state, in
+// reverse order of first touch. Errors are ignored - there's no more
+// straightforward recovery from a backend that fails on the way in and
+// then fails again on the way out.
+func (tx *txBackend) rollback() {
+	for i := len(tx.undo) - 1; i >= 0; i-- {
+		e := tx.undo[i]
+		if e.hadValue {
+			tx.Backend.Put(e.key, e.value)
+		} else {
+			tx.Backend.Delete(e.key)
+		}
+	}
+}
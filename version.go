@@ -0,0 +1,137 @@
+// Copyright 2016 RapidLoop. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package skv
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// versionKeyPattern is the internal key a key's version counter is stored
+// under - see Version and BumpVersion. It's bookkeeping, not a user value,
+// so it carries internalKeyPrefix like the tag index does.
+const versionKeyPattern = internalKeyPrefix + "ver_%s"
+
+// Version returns key's current version counter, or 0 if it has never been
+// bumped. Unlike httpd's earlier in-memory version map, the counter is
+// stored in the backend alongside the value it tracks, so it survives a
+// restart and is shared by every KVStore handle open on the same backend -
+// which is what makes it safe to use for CAS across multiple server
+// processes, not just within one.
+func (kvs *KVStore[T]) Version(key string) (uint64, error) {
+	kvs.mu.RLock()
+	defer kvs.mu.RUnlock()
+	return kvs.versionLocked(key)
+}
+
+func (kvs *KVStore[T]) versionLocked(key string) (uint64, error) {
+	raw, err := kvs.db.Get([]byte(fmt.Sprintf(versionKeyPattern, key)))
+	if err == ErrBackendNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(raw) != 8 {
+		return 0, nil
+	}
+	return binary.BigEndian.Uint64(raw), nil
+}
+
+// BumpVersion atomically increments key's version counter and returns the
+// new value.
+func (kvs *KVStore[T]) BumpVersion(key string) (uint64, error) {
+	kvs.mu.Lock()
+	defer kvs.mu.Unlock()
+	return kvs.bumpVersionLocked(key)
+}
+
+// bumpVersionLocked does the increment-and-store for BumpVersion. Callers
+// must hold kvs.mu.
+func (kvs *KVStore[T]) bumpVersionLocked(key string) (uint64, error) {
+	v, err := kvs.versionLocked(key)
+	if err != nil {
+		return 0, err
+	}
+	v++
+
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	if err := kvs.db.Put([]byte(fmt.Sprintf(versionKeyPattern, key)), buf[:]); err != nil {
+		return 0, err
+	}
+	return v, nil
+}
+
+// DeleteVersion removes key's version counter, e.g. once key itself has
+// been deleted. It is not an error if key never had one.
+func (kvs *KVStore[T]) DeleteVersion(key string) error {
+	kvs.mu.Lock()
+	defer kvs.mu.Unlock()
+	return kvs.db.Delete([]byte(fmt.Sprintf(versionKeyPattern, key)))
+}
+
+// CompareAndSwap stores value at key - like PutWithTagsAndTTL, with an
+// optional ttl and tags - then bumps key's version counter, but only if
+// key's current version matches wantVersion. A nil wantVersion skips the
+// check and always writes, bumping the version unconditionally.
+//
+// The check, write and bump all happen under a single lock acquisition, so
+// two callers racing the same compare-and-swap can't both observe a
+// matching version before either writes: composing Version, Put and
+// BumpVersion as three separate calls (httpd's original implementation)
+// left exactly that gap open. On a version mismatch, CompareAndSwap
+// leaves the store untouched and returns the current version with
+// swapped set to false.
+func (kvs *KVStore[T]) CompareAndSwap(key string, value T, ttl time.Duration, tags []string, wantVersion *uint64) (newVersion uint64, swapped bool, err error) {
+	enc, err := kvs.codec.Encode(value)
+	if err != nil {
+		return 0, false, err
+	}
+
+	newVersion, swapped, err = kvs.compareAndSwapLocked(key, enc, ttl, tags, wantVersion)
+	if err != nil || !swapped {
+		return newVersion, swapped, err
+	}
+	kvs.publish(Event[T]{Key: key, Value: value, Type: EventPut})
+	return newVersion, true, nil
+}
+
+func (kvs *KVStore[T]) compareAndSwapLocked(key string, enc []byte, ttl time.Duration, tags []string, wantVersion *uint64) (uint64, bool, error) {
+	kvs.mu.Lock()
+	defer kvs.mu.Unlock()
+
+	cur, err := kvs.versionLocked(key)
+	if err != nil {
+		return 0, false, err
+	}
+	if wantVersion != nil && cur != *wantVersion {
+		return cur, false, nil
+	}
+
+	if ttl > 0 {
+		err = kvs.db.PutWithTTL([]byte(key), enc, ttl)
+	} else {
+		err = kvs.db.Put([]byte(key), enc)
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	for _, tag := range tags {
+		if err := kvs.mergeTagLocked(kvs.db, tag, map[string]struct{}{key: {}}); err != nil {
+			return 0, false, err
+		}
+	}
+
+	v := cur + 1
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	if err := kvs.db.Put([]byte(fmt.Sprintf(versionKeyPattern, key)), buf[:]); err != nil {
+		return 0, false, err
+	}
+	return v, true, nil
+}
@@ -0,0 +1,99 @@
+package skv
+
+import (
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestCompareAndSwap(t *testing.T) {
+	os.RemoveAll("skv-test.db")
+	db, err := Open[string]("skv-test.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	v0 := uint64(0)
+	v, swapped, err := db.CompareAndSwap("key1", "value1", 0, nil, &v0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !swapped || v != 1 {
+		t.Fatalf("got (v=%d, swapped=%v), expected (v=1, swapped=true)", v, swapped)
+	}
+
+	// A stale wantVersion must fail and leave the store untouched.
+	v, swapped, err = db.CompareAndSwap("key1", "value2", 0, nil, &v0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if swapped || v != 1 {
+		t.Fatalf("got (v=%d, swapped=%v), expected (v=1, swapped=false)", v, swapped)
+	}
+	val, err := db.Get("key1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val != "value1" {
+		t.Fatalf("got %q, expected the stale swap to have left value1 in place", val)
+	}
+
+	// A nil wantVersion is unconditional.
+	v, swapped, err = db.CompareAndSwap("key1", "value3", 0, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !swapped || v != 2 {
+		t.Fatalf("got (v=%d, swapped=%v), expected (v=2, swapped=true)", v, swapped)
+	}
+}
+
+// TestCompareAndSwapSerializesConcurrentCallers reproduces the
+// check-then-act race a naive Version+Put+BumpVersion composition would
+// have: many goroutines racing the same compare-and-swap against key1,
+// each retrying against the version it last observed. With the
+// check/write/bump now atomic, exactly one of them can succeed per
+// version, so the version should advance by exactly one per successful
+// attempt, and never more than one goroutine should report success for
+// the same starting version.
+func TestCompareAndSwapSerializesConcurrentCallers(t *testing.T) {
+	os.RemoveAll("skv-test.db")
+	db, err := Open[string]("skv-test.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	const n = 50
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	successes := 0
+
+	v0 := uint64(0)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, swapped, err := db.CompareAndSwap("key1", "value1", 0, nil, &v0); err != nil {
+				t.Error(err)
+			} else if swapped {
+				mu.Lock()
+				successes++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if successes != 1 {
+		t.Fatalf("got %d callers succeed against the same starting version, expected exactly 1", successes)
+	}
+	v, err := db.Version("key1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != 1 {
+		t.Fatalf("got version %d after the race, expected 1", v)
+	}
+}
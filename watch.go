@@ -0,0 +1,268 @@
+// Copyright 2016 RapidLoop. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package skv
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EventType identifies what kind of change a watched Event represents.
+type EventType int
+
+const (
+	EventPut EventType = iota
+	EventDelete
+	EventExpire
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventPut:
+		return "put"
+	case EventDelete:
+		return "delete"
+	case EventExpire:
+		return "expire"
+	default:
+		return "unknown"
+	}
+}
+
+// Event describes one change to a watched key. Value is the zero value of T
+// for EventDelete and EventExpire, since the deleted value is no longer
+// available to read back.
+type Event[T any] struct {
+	Key   string
+	Value T
+	Type  EventType
+}
+
+// BackpressurePolicy controls what a watcher does when its channel is full.
+type BackpressurePolicy int
+
+const (
+	// BackpressureBlock makes the write that triggered the event wait until
+	// the watcher has room to receive it. No events are lost, but a slow
+	// subscriber can stall writers - this is the default.
+	BackpressureBlock BackpressurePolicy = iota
+
+	// BackpressureDropOldest discards the oldest buffered event to make
+	// room for the new one, so a slow subscriber never blocks a writer.
+	BackpressureDropOldest
+)
+
+// WatchOption configures a Watch subscription.
+type WatchOption func(*watchConfig)
+
+type watchConfig struct {
+	backpressure BackpressurePolicy
+	bufferSize   int
+}
+
+// WithBackpressure sets how a Watch channel behaves once full. The default
+// is BackpressureBlock.
+func WithBackpressure(p BackpressurePolicy) WatchOption {
+	return func(c *watchConfig) { c.backpressure = p }
+}
+
+// WithBufferSize sets the buffer size of a Watch channel. The default is 16.
+func WithBufferSize(n int) WatchOption {
+	return func(c *watchConfig) { c.bufferSize = n }
+}
+
+// watcher is one subscription registered through Watch.
+type watcher[T any] struct {
+	prefix       string
+	ch           chan Event[T]
+	done         chan struct{}
+	backpressure BackpressurePolicy
+
+	// sendMu serializes send against unwatch's close(w.ch). publish can
+	// call send concurrently with unwatch removing and tearing down the
+	// same watcher (it copies the watcher list before unwatch can have
+	// taken w out of it), and sending on a channel that's concurrently
+	// closed panics - so unwatch holds sendMu across its close(w.ch) to
+	// guarantee no send is (or can start) mid-select on w.ch at that
+	// point.
+	sendMu sync.Mutex
+}
+
+func (w *watcher[T]) matches(key string) bool {
+	return strings.HasPrefix(key, w.prefix)
+}
+
+func (w *watcher[T]) send(ev Event[T]) {
+	w.sendMu.Lock()
+	defer w.sendMu.Unlock()
+
+	select {
+	case <-w.done:
+		return
+	default:
+	}
+
+	if w.backpressure == BackpressureDropOldest {
+		for {
+			select {
+			case w.ch <- ev:
+				return
+			case <-w.done:
+				return
+			default:
+			}
+			select {
+			case <-w.ch:
+			default:
+			}
+		}
+	}
+	select {
+	case w.ch <- ev:
+	case <-w.done:
+	}
+}
+
+// Watch subscribes to every Put and Delete, and every TTL expiry skv
+// notices, for keys equal to or prefixed by keyOrPrefix - a bare key only
+// ever matches itself and any longer keys sharing that prefix, the same
+// matching GetWithPrefix already uses. The returned channel is closed, and
+// the subscription dropped, when ctx is done or the KVStore is Closed.
+func (kvs *KVStore[T]) Watch(ctx context.Context, keyOrPrefix string, opts ...WatchOption) (<-chan Event[T], error) {
+	cfg := watchConfig{backpressure: BackpressureBlock, bufferSize: 16}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	w := &watcher[T]{
+		prefix:       keyOrPrefix,
+		ch:           make(chan Event[T], cfg.bufferSize),
+		done:         make(chan struct{}),
+		backpressure: cfg.backpressure,
+	}
+
+	kvs.watchMu.Lock()
+	kvs.watchers = append(kvs.watchers, w)
+	kvs.startExpiryScannerLocked()
+	kvs.watchMu.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-kvs.closed:
+		}
+		kvs.unwatch(w)
+	}()
+
+	return w.ch, nil
+}
+
+func (kvs *KVStore[T]) unwatch(w *watcher[T]) {
+	kvs.watchMu.Lock()
+	for i, cur := range kvs.watchers {
+		if cur == w {
+			kvs.watchers = append(kvs.watchers[:i], kvs.watchers[i+1:]...)
+			break
+		}
+	}
+	kvs.watchMu.Unlock()
+
+	// Closing done first lets any send already blocked in its select -
+	// and any that manages to start before we take sendMu below - notice
+	// and return on its own, rather than deadlocking waiting for sendMu.
+	close(w.done)
+
+	// Taking sendMu here can't deadlock: every send either hasn't started
+	// (and will see w.done closed and return immediately) or is in its
+	// blocking select, which w.done being closed has just made ready.
+	// Once we have it, no send can be in progress, so it's safe to close
+	// w.ch.
+	w.sendMu.Lock()
+	w.sendMu.Unlock()
+	close(w.ch)
+}
+
+// publish fans ev out to every watcher whose prefix matches ev.Key.
+func (kvs *KVStore[T]) publish(ev Event[T]) {
+	kvs.watchMu.Lock()
+	watchers := make([]*watcher[T], len(kvs.watchers))
+	copy(watchers, kvs.watchers)
+	kvs.watchMu.Unlock()
+
+	for _, w := range watchers {
+		if w.matches(ev.Key) {
+			w.send(ev)
+		}
+	}
+}
+
+// expiryPollInterval is how often the background goroutine started by the
+// first Watch call checks for TTL expiries.
+const expiryPollInterval = 1 * time.Second
+
+// startExpiryScannerLocked starts watchExpiries the first time it's needed.
+// Callers must hold kvs.watchMu.
+func (kvs *KVStore[T]) startExpiryScannerLocked() {
+	if kvs.expiryScannerStarted {
+		return
+	}
+	kvs.expiryScannerStarted = true
+	kvs.watchWG.Add(1)
+	go kvs.watchExpiries()
+}
+
+// watchExpiries polls GetKeys on an interval and synthesizes an
+// EventExpire for every key that disappears between polls.
+//
+// It only detects expiry, it doesn't cause it: GetKeys (via the Backend's
+// Sift) already prunes expired entries as a side effect on every Backend in
+// this package, so polling is sufficient to notice the prune rather than
+// needing to trigger it itself. A key that's deleted and re-Put within one
+// poll interval looks the same as one that merely expired; skv doesn't try
+// to tell those apart, and emits EventExpire either way once it notices the
+// gap. Deleting through Delete (rather than letting a TTL lapse) still gets
+// its own EventDelete at the time of the call, before any poll would run.
+func (kvs *KVStore[T]) watchExpiries() {
+	defer kvs.watchWG.Done()
+
+	known := make(map[string]struct{})
+	if keys, err := kvs.GetKeys(); err == nil {
+		for _, k := range keys {
+			known[k] = struct{}{}
+		}
+	}
+
+	ticker := time.NewTicker(expiryPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-kvs.closed:
+			return
+		case <-ticker.C:
+		}
+
+		keys, err := kvs.GetKeys()
+		if err != nil {
+			continue
+		}
+		current := make(map[string]struct{}, len(keys))
+		for _, k := range keys {
+			current[k] = struct{}{}
+		}
+
+		for k := range known {
+			if _, ok := current[k]; ok {
+				continue
+			}
+			kvs.mu.Lock()
+			kvs.untagAllLocked(kvs.db, k)
+			kvs.mu.Unlock()
+			kvs.publish(Event[T]{Key: k, Type: EventExpire})
+		}
+		known = current
+	}
+}
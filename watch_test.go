@@ -0,0 +1,263 @@
+package skv
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWatchPutDelete(t *testing.T) {
+	os.RemoveAll("skv-test.db")
+	db, err := Open[string]("skv-test.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch, err := db.Watch(ctx, "key1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.Put("key1", "value1"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case ev := <-ch:
+		if ev.Type != EventPut || ev.Key != "key1" || ev.Value != "value1" {
+			t.Fatalf("got %+v, expected a put event for key1=value1", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for put event")
+	}
+
+	if err := db.Delete("key1"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case ev := <-ch:
+		if ev.Type != EventDelete || ev.Key != "key1" {
+			t.Fatalf("got %+v, expected a delete event for key1", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for delete event")
+	}
+}
+
+func TestWatchPrefix(t *testing.T) {
+	os.RemoveAll("skv-test.db")
+	db, err := Open[string]("skv-test.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch, err := db.Watch(ctx, "pfx-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.Put("other-key", "value"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Put("pfx-key1", "value1"); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case ev := <-ch:
+		if ev.Key != "pfx-key1" {
+			t.Fatalf("got event for %q, expected pfx-key1", ev.Key)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for put event")
+	}
+}
+
+func TestWatchClosedByCancel(t *testing.T) {
+	os.RemoveAll("skv-test.db")
+	db, err := Open[string]("skv-test.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := db.Watch(ctx, "key1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Fatal("expected channel to be closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for channel to close")
+	}
+}
+
+func TestWatchClosedByStoreClose(t *testing.T) {
+	os.RemoveAll("skv-test.db")
+	db, err := Open[string]("skv-test.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ch, err := db.Watch(context.Background(), "key1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Fatal("expected channel to be closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for channel to close")
+	}
+}
+
+// TestWatchExpireFS guards against fsBackend.Sift returning expired keys:
+// watchExpiries only notices an expiry because GetKeys (via Sift) stops
+// returning the key, so a backend that doesn't prune expired entries from
+// Sift never fires EventExpire.
+func TestWatchExpireFS(t *testing.T) {
+	os.RemoveAll("skv-test-fs")
+	defer os.RemoveAll("skv-test-fs")
+
+	db, err := Open[string]("fs://skv-test-fs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch, err := db.Watch(ctx, "key1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The TTL has to outlive one expiryPollInterval so watchExpiries's
+	// first poll sees key1 as known before a later poll sees it gone -
+	// a TTL shorter than the poll interval would expire between polls
+	// without ever being observed as present.
+	if err := db.PutWithTTL("key1", "value1", 3*expiryPollInterval/2); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case ev := <-ch:
+		if ev.Type != EventPut {
+			t.Fatalf("got %+v, expected a put event", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for put event")
+	}
+
+	select {
+	case ev := <-ch:
+		if ev.Type != EventExpire || ev.Key != "key1" {
+			t.Fatalf("got %+v, expected an expire event for key1", ev)
+		}
+	case <-time.After(4 * expiryPollInterval):
+		t.Fatal("timed out waiting for expire event")
+	}
+}
+
+// TestDeleteDoesNotHoldLockWhilePublishing guards against Delete holding
+// kvs.mu while publish blocks on a full, undrained watcher channel - which
+// would stall unrelated operations on other keys too. It doesn't assert
+// that Delete itself returns quickly (publish for key1 is still blocking in
+// that same goroutine), only that an operation on an unrelated key2 isn't
+// stuck waiting on kvs.mu behind it.
+func TestDeleteDoesNotHoldLockWhilePublishing(t *testing.T) {
+	os.RemoveAll("skv-test.db")
+	db, err := Open[string]("skv-test.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := db.Put("key1", "value1"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := db.Watch(ctx, "key1", WithBufferSize(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deleteDone := make(chan error, 1)
+	go func() { deleteDone <- db.Delete("key1") }()
+	time.Sleep(50 * time.Millisecond) // let Delete reach its blocked publish call
+
+	putDone := make(chan error, 1)
+	go func() { putDone <- db.PutWithTags("key2", "value2", []string{"tagA"}) }()
+
+	select {
+	case err := <-putDone:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("PutWithTags on an unrelated key blocked behind Delete's publish")
+	}
+
+	<-ch // unblock Delete's publish call
+	if err := <-deleteDone; err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+}
+
+// TestWatchCancelRacesPublish reproduces the scenario that used to trip
+// "send on closed channel": a live stream of Puts racing a context cancel
+// that tears the watcher down concurrently. It doesn't assert much beyond
+// "no panic, no -race report" - that's the point of the test.
+func TestWatchCancelRacesPublish(t *testing.T) {
+	os.RemoveAll("skv-test.db")
+	db, err := Open[string]("skv-test.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := db.Watch(ctx, "key1", WithBufferSize(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for range ch {
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			db.Put("key1", "value1")
+		}()
+	}
+	cancel()
+	wg.Wait()
+}